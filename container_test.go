@@ -0,0 +1,56 @@
+package unhtml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnmarshalMap(t *testing.T) {
+	type Users struct {
+		Users map[string]string `unhtml:"//user,key=@id"`
+	}
+
+	html := `<root><user id="a">Alice</user><user id="b">Bob</user></root>`
+
+	d, err := NewDecoder(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("Failed parsing html: %s", err)
+	}
+
+	var result Users
+	if err := d.Unmarshal(&result); err != nil {
+		t.Fatalf("Failed unmarshalling: %s", err)
+	}
+
+	want := map[string]string{"a": "Alice", "b": "Bob"}
+	if len(result.Users) != len(want) {
+		t.Fatalf("got %v, want %v", result.Users, want)
+	}
+	for k, v := range want {
+		if result.Users[k] != v {
+			t.Errorf("Users[%q] = %q, want %q", k, result.Users[k], v)
+		}
+	}
+}
+
+func TestUnmarshalArray(t *testing.T) {
+	type List struct {
+		Items [2]int `unhtml:"//li"`
+	}
+
+	html := `<ul><li>1</li><li>2</li><li>3</li></ul>`
+
+	d, err := NewDecoder(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("Failed parsing html: %s", err)
+	}
+
+	var result List
+	if err := d.Unmarshal(&result); err != nil {
+		t.Fatalf("Failed unmarshalling: %s", err)
+	}
+
+	if result.Items != [2]int{1, 2} {
+		t.Errorf("Items = %v, want [1 2]", result.Items)
+	}
+}
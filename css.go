@@ -0,0 +1,201 @@
+package unhtml
+
+import (
+	"bytes"
+	"strings"
+)
+
+// CSS selector support. Rather than adding a second node-matching
+// backend, a CSS selector is translated into the equivalent xpath and
+// handed to the exact same compile/iterate pipeline xpath tags already
+// use. This keeps multinode, ,innerhtml and friends working unchanged
+// for CSS-tagged fields. A CSS selector always selects elements, so a
+// CSS-tagged field with ,attr always fails with NotAttributeError.
+//
+// A field can opt into CSS in two ways:
+//
+//	Title string `uncss:"div.commit-group > li"`
+//	Title string `unhtml:"css:div.commit-group > li"`
+//
+// Only a practical subset of CSS is supported: tag names, ".class",
+// "#id", "[attr]"/"[attr=value]" attribute selectors, and the
+// descendant (space) and child (>) combinators. Anything else returns
+// an InvalidSelectorError.
+
+// InvalidSelectorError is returned when a `uncss` tag, or a `css:`
+// prefixed `unhtml` tag, cannot be translated to xpath.
+type InvalidSelectorError string
+
+func (e InvalidSelectorError) Error() string {
+	return "unhtml: invalid css selector: " + string(e)
+}
+
+const cssPathPrefix = "css:"
+
+// cssToXPath translates a (restricted) CSS selector into an equivalent
+// relative xpath expression.
+func cssToXPath(selector string) (string, error) {
+	selector = strings.TrimSpace(selector)
+	if selector == "" {
+		return "", InvalidSelectorError(selector)
+	}
+
+	fields := strings.Fields(selector)
+
+	var b bytes.Buffer
+	combinatorIsChild := false
+
+	for _, field := range fields {
+		if field == ">" {
+			combinatorIsChild = true
+			continue
+		}
+
+		// Combinators other than descendant (space) and child (>),
+		// such as "+" (adjacent sibling) and "~" (general sibling),
+		// aren't supported; reject them here instead of letting them
+		// fall through to compoundToXPathStep, where they'd be mistaken
+		// for a bogus tag name and only fail later and opaquely when
+		// xmlpath.Compile rejects the resulting xpath.
+		if field == "+" || field == "~" {
+			return "", InvalidSelectorError(selector)
+		}
+
+		step, err := compoundToXPathStep(field)
+		if err != nil {
+			return "", InvalidSelectorError(selector)
+		}
+
+		if combinatorIsChild {
+			b.WriteByte('/')
+		} else {
+			b.WriteString("//")
+		}
+		b.WriteString(step)
+
+		combinatorIsChild = false
+	}
+
+	// A trailing ">" has no right-hand side.
+	if combinatorIsChild {
+		return "", InvalidSelectorError(selector)
+	}
+
+	if b.Len() == 0 {
+		return "", InvalidSelectorError(selector)
+	}
+
+	return "." + b.String(), nil
+}
+
+// compoundToXPathStep translates a single compound selector, e.g.
+// "div.commit-group#main[data-x]", into one xpath step such as
+// `div[contains(concat(' ',normalize-space(@class),' '),' commit-group ')][@id='main'][@data-x]`.
+func compoundToXPathStep(compound string) (string, error) {
+	name, rest := leadingName(compound)
+	if name == "" {
+		name = "*"
+	} else if !isIdentifier(name) {
+		return "", InvalidSelectorError(compound)
+	}
+
+	var predicates []string
+
+	for len(rest) > 0 {
+		switch rest[0] {
+		case '.':
+			end := indexAny(rest[1:], ".#[")
+			class := rest[1 : 1+end]
+			if !isIdentifier(class) {
+				return "", InvalidSelectorError(compound)
+			}
+			predicates = append(predicates, "contains(concat(' ',normalize-space(@class),' '),' "+class+" ')")
+			rest = rest[1+end:]
+		case '#':
+			end := indexAny(rest[1:], ".#[")
+			id := rest[1 : 1+end]
+			if !isIdentifier(id) {
+				return "", InvalidSelectorError(compound)
+			}
+			predicates = append(predicates, "@id='"+id+"'")
+			rest = rest[1+end:]
+		case '[':
+			close := strings.IndexByte(rest, ']')
+			if close < 0 {
+				return "", InvalidSelectorError(compound)
+			}
+			attr := rest[1:close]
+			if eq := strings.IndexByte(attr, '='); eq >= 0 {
+				value := strings.Trim(attr[eq+1:], `"'`)
+				if !isIdentifier(attr[:eq]) || strings.ContainsRune(value, '\'') {
+					return "", InvalidSelectorError(compound)
+				}
+				predicates = append(predicates, "@"+attr[:eq]+"='"+value+"'")
+			} else {
+				if !isIdentifier(attr) {
+					return "", InvalidSelectorError(compound)
+				}
+				predicates = append(predicates, "@"+attr)
+			}
+			rest = rest[close+1:]
+		default:
+			return "", InvalidSelectorError(compound)
+		}
+	}
+
+	step := name
+	for _, p := range predicates {
+		step += "[" + p + "]"
+	}
+	return step, nil
+}
+
+// leadingName splits off the optional leading tag name of a compound
+// selector, stopping at the first class/id/attribute marker.
+func leadingName(compound string) (name, rest string) {
+	end := indexAny(compound, ".#[")
+	return compound[:end], compound[end:]
+}
+
+// isIdentifier reports whether s is a valid CSS tag/class/id/attribute
+// name, or the universal selector "*". It rejects stray combinator
+// tokens and other punctuation that don't belong in a compound selector.
+func isIdentifier(s string) bool {
+	if s == "*" {
+		return true
+	}
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '-', c == '_':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// indexAny is like strings.IndexAny but returns len(s) instead of -1
+// when none of chars occur, which is the more convenient zero value for
+// the slicing callers above.
+func indexAny(s, chars string) int {
+	if i := strings.IndexAny(s, chars); i >= 0 {
+		return i
+	}
+	return len(s)
+}
+
+// UnmarshalCSS is the CSS-selector counterpart of UnmarshalRelative: it
+// translates selector to xpath and unmarshals from there.
+func (d *Decoder) UnmarshalCSS(selector string, res interface{}) error {
+	xpath, err := cssToXPath(selector)
+	if err != nil {
+		return err
+	}
+
+	return d.UnmarshalRelative(xpath, res)
+}
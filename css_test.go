@@ -0,0 +1,47 @@
+package unhtml
+
+import "testing"
+
+func TestCSSToXPath(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     string
+	}{
+		{"li", ".//li"},
+		{"div.commit-group", ".//div[contains(concat(' ',normalize-space(@class),' '),' commit-group ')]"},
+		{"div.commit-group > li", ".//div[contains(concat(' ',normalize-space(@class),' '),' commit-group ')]/li"},
+		{"#main", ".//*[@id='main']"},
+		{"a[href]", ".//a[@href]"},
+		{`a[rel="author"]`, ".//a[@rel='author']"},
+	}
+
+	for _, test := range tests {
+		got, err := cssToXPath(test.selector)
+		if err != nil {
+			t.Errorf("cssToXPath(%q) returned error: %s", test.selector, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("cssToXPath(%q) = %q, want %q", test.selector, got, test.want)
+		}
+	}
+}
+
+func TestCSSToXPathInvalid(t *testing.T) {
+	invalid := []string{
+		"",
+		"li + li",
+		"li ~ li",
+	}
+
+	for _, selector := range invalid {
+		_, err := cssToXPath(selector)
+		if err == nil {
+			t.Errorf("cssToXPath(%q): expected InvalidSelectorError, got nil", selector)
+			continue
+		}
+		if _, ok := err.(InvalidSelectorError); !ok {
+			t.Errorf("cssToXPath(%q): expected InvalidSelectorError, got %T: %s", selector, err, err)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package unhtml
 
 import (
+	"bytes"
 	"encoding"
 	"fmt"
 	"io"
@@ -60,30 +61,54 @@ type Unmarshaler interface {
 
 // Decoder
 type Decoder struct {
+	raw  []byte
 	root *xmlpath.Node
 }
 
 // NewDecoder returns a new Decoder by using the contents of the
-// io.Reader as HTML input. The io.Reader is consumed whole and
-// contents parsed before this function returns.
+// io.Reader as HTML input. The io.Reader is consumed whole before this
+// function returns, but parsing the HTML into a tree is deferred until
+// something that needs one, such as Unmarshal or UnmarshalRelative, asks
+// for it; Stream scans the raw input directly and never builds one, so a
+// caller that only streams never pays to build a tree of the whole
+// document.
 //
-// An error return means something went wrong parsing the HTML.
+// An error return means something went wrong reading from r.
 func NewDecoder(r io.Reader) (*Decoder, error) {
-	root, err := xmlpath.ParseHTML(r)
-
+	raw, err := io.ReadAll(r)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Decoder{root: root}, nil
+	return &Decoder{raw: raw}, nil
+}
+
+// tree lazily parses d.raw into an xmlpath.Node tree and caches the
+// result, so it's only ever built once, and not at all if it's never
+// asked for.
+func (d *Decoder) tree() (*xmlpath.Node, error) {
+	if d.root == nil {
+		root, err := xmlpath.ParseHTML(bytes.NewReader(d.raw))
+		if err != nil {
+			return nil, err
+		}
+		d.root = root
+	}
+
+	return d.root, nil
 }
 
 // Unmarshal tries to fill the value given with the input previously
 // given to the Decoder. See `unhtml.Unmarshal` for full docs.
 func (d *Decoder) Unmarshal(res interface{}) error {
+	root, err := d.tree()
+	if err != nil {
+		return err
+	}
+
 	st := &state{}
 
-	st.unmarshal(d.root, reflect.ValueOf(res))
+	st.unmarshal(root, reflect.ValueOf(res))
 
 	return st.firstError
 }
@@ -103,6 +128,11 @@ func (d *Decoder) UnmarshalRelative(path string, res interface{}) error {
 		return err
 	}
 
+	root, err := d.tree()
+	if err != nil {
+		return err
+	}
+
 	var nodes []*xmlpath.Node
 	var st = &state{}
 
@@ -110,7 +140,7 @@ func (d *Decoder) UnmarshalRelative(path string, res interface{}) error {
 
 	isSlice := v.Kind() == reflect.Slice || v.Kind() == reflect.Array
 
-	for iter := xpath.Iter(d.root); iter.Next(); {
+	for iter := xpath.Iter(root); iter.Next(); {
 		nodes = append(nodes, iter.Node())
 
 		// Only use the first node we find if `res` is not a slice or array
@@ -195,6 +225,36 @@ func (d *state) multinode(nodes []*xmlpath.Node, value reflect.Value) {
 	}
 }
 
+// unmarshalMap fills a map-typed field from nodes, deriving each entry's
+// key by evaluating keyPath against that node. keyPath comes from the
+// field's ,key= tag option, see fieldInfo.
+func (d *state) unmarshalMap(nodes []*xmlpath.Node, value reflect.Value, keyPath *xmlpath.Path) {
+	if value.Type().Key().Kind() != reflect.String {
+		d.saveError(&UnmarshalTypeError{"map key", value.Type().Key()})
+		return
+	}
+
+	if value.IsNil() {
+		value.Set(reflect.MakeMap(value.Type()))
+	}
+
+	elemType := value.Type().Elem()
+
+	for _, node := range nodes {
+		iter := keyPath.Iter(node)
+		if !iter.Next() {
+			d.saveError(NoNodesAvailable("key path did not match"))
+			continue
+		}
+		key := iter.Node().String()
+
+		elem := reflect.New(elemType).Elem()
+		d.unmarshal(node, elem)
+
+		value.SetMapIndex(reflect.ValueOf(key).Convert(value.Type().Key()), elem)
+	}
+}
+
 func (d *state) unmarshal(root *xmlpath.Node, rv reflect.Value) {
 	m, tm, value := indirect(rv)
 
@@ -219,6 +279,10 @@ func (d *state) unmarshal(root *xmlpath.Node, rv reflect.Value) {
 	case reflect.Struct:
 		d.unmarshalStruct(root, value)
 	case reflect.Array:
+		// A single node matched against an array-typed field; reuse
+		// multinode's bounded fill so it lands in index 0 without
+		// panicking on a zero-length array.
+		d.multinode([]*xmlpath.Node{root}, value)
 	case reflect.Slice:
 		// Short-path for []byte and []rune
 		t := value.Type().Elem().Kind()
@@ -277,8 +341,6 @@ func (d *state) unmarshal(root *xmlpath.Node, rv reflect.Value) {
 }
 
 func (d *state) unmarshalStruct(root *xmlpath.Node, value reflect.Value) {
-	valueType := value.Type()
-
 	if value.Kind() != reflect.Struct {
 		err := &InvalidUnmarshalError{
 			Type: value.Type(),
@@ -288,58 +350,128 @@ func (d *state) unmarshalStruct(root *xmlpath.Node, value reflect.Value) {
 		return
 	}
 
-	for i := 0; i < value.NumField(); i++ {
-		field := value.Field(i)
-		structField := valueType.Field(i)
+	info, err := getTypeInfo(value.Type())
+	if err != nil {
+		d.saveError(err)
+		return
+	}
 
-		// Find the struct tag if any
-		path := structField.Tag.Get("unhtml")
+	for _, fi := range info.fields {
+		field := value.Field(fi.index)
 
-		if path == "" {
-			// Skip fields with no tag, since we require an xpath
+		if !field.CanSet() {
 			if debug {
-				fmt.Println("Skipping field due to lack of xpath: ", field)
+				fmt.Println("Skipping field due to unsettability: ", field)
 			}
+			// TODO: Some way to feedback to the user
 			continue
 		}
 
-		if !field.CanSet() {
-			if debug {
-				fmt.Println("Skipping field due to unsettability: ", field)
+		opts := fi.opts
+
+		var nodes []*xmlpath.Node
+		if fi.path == nil {
+			// No xpath given: the current node itself is the target,
+			// useful together with ,chardata or ,innerhtml.
+			nodes = []*xmlpath.Node{root}
+		} else {
+			for iter := fi.path.Iter(root); iter.Next(); {
+				nodes = append(nodes, iter.Node())
+			}
+		}
+
+		if debug {
+			fmt.Printf("Executed %s with %d resulting nodes\n", opts.path, len(nodes))
+		}
+
+		if len(nodes) < 1 {
+			if !opts.omitempty {
+				d.saveError(NoNodesAvailable(opts.path))
+			} else if debug {
+				fmt.Println("Xpath did not match any nodes: ", opts.path)
 			}
-			// TODO: Some way to feedback to the user
 			continue
 		}
 
-		var (
-			nodes = make([]*xmlpath.Node, 0, 12)
-			xpath = xmlpath.MustCompile(path)
-		)
+		if opts.attr && !isAttrPath(opts.path) {
+			d.saveError(NotAttributeError(opts.path))
+			continue
+		}
 
-		for iter := xpath.Iter(root); iter.Next(); {
-			node := iter.Node()
+		if opts.innerhtml {
+			d.unmarshalInnerHTML(nodes[0], field)
+			continue
+		}
 
-			nodes = append(nodes, node)
+		if opts.chardata {
+			d.unmarshalChardata(nodes[0], field)
+			continue
 		}
 
-		if debug {
-			fmt.Printf("Executed %s with %d resulting nodes\n", path, len(nodes))
+		if field.Kind() == reflect.Map {
+			d.unmarshalMap(nodes, field, fi.keyPath)
+			continue
 		}
 
 		if len(nodes) > 1 {
 			d.multinode(nodes, field)
 			continue
-		} else if len(nodes) < 1 {
-			if debug {
-				fmt.Println("Xpath did not match any nodes: ", path)
-			}
-			continue
 		}
 
 		d.unmarshal(nodes[0], field)
 	}
 }
 
+// unmarshalInnerHTML fills a string or []byte field with node.Bytes(),
+// which, like String(), is the text of every descendant concatenated;
+// element tags and attributes are not included. Any other field type
+// gets an UnmarshalTypeError.
+func (d *state) unmarshalInnerHTML(node *xmlpath.Node, field reflect.Value) {
+	_, _, value := indirect(field)
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(string(node.Bytes()))
+	case reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			value.Set(reflect.ValueOf(node.Bytes()))
+			return
+		}
+		fallthrough
+	default:
+		d.saveError(&UnmarshalTypeError{"innerhtml", value.Type()})
+	}
+}
+
+// chardataPath selects only the text nodes that are direct children of
+// the context node, as opposed to d.unmarshal's default String()/Bytes()
+// handling which concatenates text from every descendant.
+var chardataPath = xmlpath.MustCompile("text()")
+
+// unmarshalChardata fills field with node's own direct text content,
+// skipping over text that belongs to any nested child elements.
+func (d *state) unmarshalChardata(node *xmlpath.Node, field reflect.Value) {
+	var text string
+	for iter := chardataPath.Iter(node); iter.Next(); {
+		text += iter.Node().String()
+	}
+
+	_, _, value := indirect(field)
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(text)
+	case reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			value.Set(reflect.ValueOf([]byte(text)))
+			return
+		}
+		fallthrough
+	default:
+		d.saveError(&UnmarshalTypeError{"chardata", value.Type()})
+	}
+}
+
 // indirect walks down v allocating pointers as needed until it gets to a non-pointer
 //
 // indirect original can be found in the stdlib encoding/json, credit to Go authors
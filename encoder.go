@@ -0,0 +1,402 @@
+package unhtml
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Marshaler is the write-side counterpart of Unmarshaler. Types
+// implementing it are asked to render themselves directly instead of
+// going through the struct tag machinery.
+type Marshaler interface {
+	MarshalHTML() ([]byte, error)
+}
+
+// UnsupportedPathError is returned by Marshal when a struct tag uses an
+// xpath expression outside of the subset Marshal can invert. See Marshal
+// for exactly which expressions are supported.
+type UnsupportedPathError string
+
+func (e UnsupportedPathError) Error() string {
+	return "unhtml: unsupported path for marshalling: " + string(e)
+}
+
+// Marshal returns the HTML encoding of v.
+//
+// Marshal only understands a subset of the xpath expressions accepted by
+// Unmarshal, since arbitrary xpath has no well defined inverse. A tag is
+// invertible if it is built out of nothing but:
+//
+//	foo        a child element named foo
+//	foo/bar    a nested child element
+//	@bar       an attribute named bar on the current element
+//	foo[N]     the Nth (1-indexed) foo child element
+//	.          the text content of the current element
+//
+// Any other expression, such as predicates (`foo[@id='x']`) or axes
+// (`descendant::foo`), causes Marshal to return an UnsupportedPathError.
+func Marshal(v interface{}) ([]byte, error) {
+	el, err := marshalRoot(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	el.write(&buf)
+	return buf.Bytes(), nil
+}
+
+// MarshalIndent is like Marshal but indents the output HTML with prefix
+// and indent, following the same convention as xml.MarshalIndent.
+func MarshalIndent(v interface{}, prefix, indent string) ([]byte, error) {
+	el, err := marshalRoot(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	el.writeIndent(&buf, prefix, indent, 0)
+	return buf.Bytes(), nil
+}
+
+// Encoder writes the HTML encoding of values to an internal buffer,
+// mirroring xml.Encoder. Use Marshal or MarshalIndent directly unless
+// you need to Encode several values into one Encoder in sequence.
+type Encoder struct {
+	buf bytes.Buffer
+}
+
+// Encode writes the HTML encoding of v, appending it to the Encoder's
+// buffered output. The accumulated output is available through Bytes.
+func (enc *Encoder) Encode(v interface{}) error {
+	el, err := marshalRoot(v)
+	if err != nil {
+		return err
+	}
+
+	el.write(&enc.buf)
+	return nil
+}
+
+// Bytes returns the output accumulated by prior calls to Encode.
+func (enc *Encoder) Bytes() []byte {
+	return enc.buf.Bytes()
+}
+
+func marshalRoot(v interface{}) (*element, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+
+	return marshalValue(rv)
+}
+
+// element is a minimal in-memory HTML tree, just enough to render the
+// subset of structure Marshal can produce.
+type element struct {
+	name     string
+	attrs    []attr
+	text     string
+	children []*element
+}
+
+type attr struct {
+	name  string
+	value string
+}
+
+func (el *element) write(buf *bytes.Buffer) {
+	if el.name == "" {
+		// An unnamed element is either a scalar text leaf, or the
+		// synthetic root marshalStruct wraps its top-level fields in
+		// (which never gets a name of its own). Children distinguish
+		// the two: a wrapper has them, a text leaf doesn't.
+		if len(el.children) == 0 {
+			buf.WriteString(escapeText.Replace(el.text))
+			return
+		}
+
+		for _, child := range el.children {
+			child.write(buf)
+		}
+		return
+	}
+
+	el.writeOpenTag(buf)
+	buf.WriteString(escapeText.Replace(el.text))
+	for _, child := range el.children {
+		child.write(buf)
+	}
+	buf.WriteString("</")
+	buf.WriteString(el.name)
+	buf.WriteByte('>')
+}
+
+func (el *element) writeOpenTag(buf *bytes.Buffer) {
+	buf.WriteByte('<')
+	buf.WriteString(el.name)
+	for _, a := range el.attrs {
+		buf.WriteByte(' ')
+		buf.WriteString(a.name)
+		buf.WriteString(`="`)
+		buf.WriteString(escapeAttr.Replace(a.value))
+		buf.WriteByte('"')
+	}
+	buf.WriteByte('>')
+}
+
+// escapeText escapes the characters that would otherwise be misread as
+// markup when written into a text node, the same set html/template
+// escapes for HTML text.
+var escapeText = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+)
+
+// escapeAttr escapes the characters that would otherwise be misread as
+// markup or break out of a double-quoted attribute value.
+var escapeAttr = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&quot;",
+)
+
+func (el *element) writeIndent(buf *bytes.Buffer, prefix, indent string, depth int) {
+	pad := func() {
+		buf.WriteString(prefix)
+		for i := 0; i < depth; i++ {
+			buf.WriteString(indent)
+		}
+	}
+
+	if el.name == "" {
+		if len(el.children) == 0 {
+			pad()
+			buf.WriteString(escapeText.Replace(el.text))
+			buf.WriteByte('\n')
+			return
+		}
+
+		for _, child := range el.children {
+			child.writeIndent(buf, prefix, indent, depth)
+		}
+		return
+	}
+
+	pad()
+	el.writeOpenTag(buf)
+	if el.text != "" {
+		buf.WriteString(escapeText.Replace(el.text))
+	}
+	if len(el.children) > 0 {
+		buf.WriteByte('\n')
+		for _, child := range el.children {
+			child.writeIndent(buf, prefix, indent, depth+1)
+		}
+		pad()
+	}
+	buf.WriteString("</")
+	buf.WriteString(el.name)
+	buf.WriteString(">\n")
+}
+
+// marshalValue converts a single Go value into an element tree rooted at
+// an unnamed text element, a struct element, or a scalar text element.
+func marshalValue(rv reflect.Value) (*element, error) {
+	if rv.IsValid() && rv.Type().Implements(marshalerType) {
+		m := rv.Interface().(Marshaler)
+		b, err := m.MarshalHTML()
+		if err != nil {
+			return nil, err
+		}
+		return &element{text: string(b)}, nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		return marshalStruct(rv)
+	case reflect.String:
+		return &element{text: rv.String()}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &element{text: strconv.FormatInt(rv.Int(), 10)}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &element{text: strconv.FormatUint(rv.Uint(), 10)}, nil
+	case reflect.Float32, reflect.Float64:
+		return &element{text: strconv.FormatFloat(rv.Float(), 'f', -1, rv.Type().Bits())}, nil
+	default:
+		return nil, &UnmarshalTypeError{"<value>", rv.Type()}
+	}
+}
+
+var marshalerType = reflect.TypeOf((*Marshaler)(nil)).Elem()
+
+// marshalStruct builds an element tree for a struct value by walking its
+// fields in declaration order, the mirror image of unmarshalStruct.
+func marshalStruct(rv reflect.Value) (*element, error) {
+	root := &element{}
+	valueType := rv.Type()
+
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Field(i)
+		structField := valueType.Field(i)
+
+		if structField.PkgPath != "" {
+			// Unexported field: mirrors unmarshalStruct's field.CanSet()
+			// check. Reading it via rv.Interface() below (for a
+			// Marshaler, say) would panic.
+			continue
+		}
+
+		path, _ := splitTagOptions(structField.Tag.Get("unhtml"))
+		if path == "" {
+			continue
+		}
+
+		segments, err := parseEncodePath(path)
+		if err != nil {
+			return nil, err
+		}
+
+		child, err := marshalValue(field)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := root.place(segments, child); err != nil {
+			return nil, err
+		}
+	}
+
+	return root, nil
+}
+
+// place attaches child at the location described by segments, creating
+// intermediate elements as needed.
+func (el *element) place(segments []pathSegment, child *element) error {
+	cur := el
+	for i, seg := range segments {
+		last := i == len(segments)-1
+
+		switch {
+		case seg.text:
+			if !last {
+				return UnsupportedPathError("text node in the middle of a path")
+			}
+			cur.text = child.text
+			return nil
+		case seg.attr:
+			if !last {
+				return UnsupportedPathError("attribute in the middle of a path")
+			}
+			cur.attrs = append(cur.attrs, attr{name: seg.name, value: child.text})
+			return nil
+		default:
+			// Pad with empty siblings so that the element found or
+			// placed below is the Nth of its name.
+			for seg.index > 1 && findNamedChild(cur.children, seg.name, seg.index-1) == nil {
+				cur.children = append(cur.children, &element{name: seg.name})
+			}
+
+			if last {
+				child.name = seg.name
+				if seg.index > 0 {
+					if existing := findNamedChild(cur.children, seg.name, seg.index); existing != nil {
+						existing.text = child.text
+						existing.attrs = append(existing.attrs, child.attrs...)
+						existing.children = append(existing.children, child.children...)
+						return nil
+					}
+				}
+				cur.children = append(cur.children, child)
+				return nil
+			}
+
+			next := findNamedChild(cur.children, seg.name, seg.index)
+			if next == nil {
+				next = &element{name: seg.name}
+				cur.children = append(cur.children, next)
+			}
+			cur = next
+		}
+	}
+
+	return nil
+}
+
+func findNamedChild(children []*element, name string, index int) *element {
+	count := 0
+	for _, c := range children {
+		if c.name == name {
+			count++
+			if index == 0 || count == index {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// pathSegment is one step of an invertible path, e.g. "foo", "@bar" or
+// "foo[2]".
+type pathSegment struct {
+	name  string
+	attr  bool
+	text  bool
+	index int // 1-indexed; 0 means unconstrained
+}
+
+// parseEncodePath parses the documented invertible subset of xpath used
+// by Marshal. Anything outside that subset returns UnsupportedPathError.
+func parseEncodePath(path string) ([]pathSegment, error) {
+	if path == "." || path == "text()" {
+		return []pathSegment{{text: true}}, nil
+	}
+
+	parts := strings.Split(path, "/")
+	segments := make([]pathSegment, 0, len(parts))
+
+	for _, part := range parts {
+		switch {
+		case part == "":
+			return nil, UnsupportedPathError(path)
+		case strings.HasPrefix(part, "@"):
+			segments = append(segments, pathSegment{name: part[1:], attr: true})
+		case strings.Contains(part, "["):
+			name, idx, err := splitIndex(part)
+			if err != nil {
+				return nil, UnsupportedPathError(path)
+			}
+			segments = append(segments, pathSegment{name: name, index: idx})
+		case strings.ContainsAny(part, "'\":()*@"):
+			return nil, UnsupportedPathError(path)
+		default:
+			segments = append(segments, pathSegment{name: part})
+		}
+	}
+
+	if len(segments) == 0 {
+		return nil, UnsupportedPathError(path)
+	}
+
+	return segments, nil
+}
+
+// splitIndex parses a "name[N]" segment into its name and 1-indexed N.
+func splitIndex(part string) (string, int, error) {
+	open := strings.IndexByte(part, '[')
+	if open <= 0 || part[len(part)-1] != ']' {
+		return "", 0, UnsupportedPathError(part)
+	}
+
+	idx, err := strconv.Atoi(part[open+1 : len(part)-1])
+	if err != nil {
+		return "", 0, err
+	}
+
+	return part[:open], idx, nil
+}
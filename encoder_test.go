@@ -0,0 +1,100 @@
+package unhtml
+
+import "testing"
+
+type marshalTest struct {
+	value    interface{}
+	expected string
+}
+
+var marshalTests = []marshalTest{
+	{
+		value: struct {
+			A string `unhtml:"div"`
+		}{"Hello"},
+		expected: "<div>Hello</div>",
+	},
+	{
+		value: struct {
+			A int `unhtml:"span"`
+		}{42},
+		expected: "<span>42</span>",
+	},
+	{
+		value: struct {
+			A struct {
+				Href string `unhtml:"@href"`
+			} `unhtml:"a"`
+		}{struct {
+			Href string `unhtml:"@href"`
+		}{"http://example.com"}},
+		expected: `<a href="http://example.com"></a>`,
+	},
+	{
+		value: struct {
+			A string `unhtml:"div"`
+		}{"<script>alert(1)</script> & co"},
+		expected: "<div>&lt;script&gt;alert(1)&lt;/script&gt; &amp; co</div>",
+	},
+	{
+		value: struct {
+			A struct {
+				Title string `unhtml:"@title"`
+			} `unhtml:"div"`
+		}{struct {
+			Title string `unhtml:"@title"`
+		}{`a"b & c`}},
+		expected: `<div title="a&quot;b &amp; c"></div>`,
+	},
+}
+
+func TestMarshal(t *testing.T) {
+	for _, test := range marshalTests {
+		b, err := Marshal(test.value)
+		if err != nil {
+			t.Errorf("Marshal(%#v) returned error: %s", test.value, err)
+			continue
+		}
+
+		if string(b) != test.expected {
+			t.Errorf("Marshal(%#v) = %q, want %q", test.value, b, test.expected)
+		}
+	}
+}
+
+type marshalHTMLStub string
+
+func (s marshalHTMLStub) MarshalHTML() ([]byte, error) {
+	return []byte(s), nil
+}
+
+func TestMarshalSkipsUnexportedFields(t *testing.T) {
+	type S struct {
+		A string          `unhtml:"div"`
+		b marshalHTMLStub `unhtml:"span"`
+	}
+
+	v := S{A: "Hello"}
+	v.b = "should never be read"
+
+	b, err := Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal returned error: %s", err)
+	}
+
+	if want := "<div>Hello</div>"; string(b) != want {
+		t.Errorf("Marshal = %q, want %q", b, want)
+	}
+}
+
+func TestMarshalUnsupportedPath(t *testing.T) {
+	type Bad struct {
+		A string `unhtml:"div[@id='x']"`
+	}
+
+	if _, err := Marshal(Bad{"Hello"}); err == nil {
+		t.Errorf("expected UnsupportedPathError, got nil")
+	} else if _, ok := err.(UnsupportedPathError); !ok {
+		t.Errorf("expected UnsupportedPathError, got %T: %s", err, err)
+	}
+}
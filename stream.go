@@ -0,0 +1,299 @@
+package unhtml
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+
+	"golang.org/x/net/html"
+	"gopkg.in/xmlpath.v1"
+)
+
+// UnsupportedStreamPathError is returned by Stream when path can't be
+// evaluated incrementally. Streaming only supports a single step naming a
+// concrete tag, optionally with predicates, e.g. "//li" or
+// ".//tr[@class='odd']": anything that depends on ancestor structure
+// (more than one step) would need the whole document in a tree to
+// evaluate, which is exactly what Stream exists to avoid building.
+type UnsupportedStreamPathError string
+
+func (e UnsupportedStreamPathError) Error() string {
+	return "unhtml: unsupported path for streaming: " + string(e)
+}
+
+// selfClosingSiblings are the handful of HTML5 elements whose end tag is
+// commonly left out because a following sibling of the same name closes
+// it implicitly ("<li>1<li>2" instead of "<li>1</li><li>2</li>"), which
+// covers the common "listing page" scraping target this package is
+// mostly used for. nextFragment treats a start tag of one of these as
+// closing a still-open sibling of the same name.
+//
+// Table rows and cells (tr/td/th) have a similar rule in real HTML5, but
+// it closes across different tag names (a new tr also closes an open
+// td), which nextFragment's single-tag-name bookkeeping can't express.
+// They're deliberately left out here: streaming a table whose rows or
+// cells omit their closing tags fails with a parse error instead of
+// silently merging rows. Write the closing tags explicitly if that
+// matters for your input.
+var selfClosingSiblings = map[string]bool{
+	"li": true, "dt": true, "dd": true, "p": true,
+	"rt": true, "rp": true, "option": true, "optgroup": true,
+}
+
+// voidElements never have a closing tag at all.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"param": true, "source": true, "track": true, "wbr": true,
+}
+
+// Stream returns an iterator over the matches of path without parsing
+// the whole document into a tree first: it scans the underlying HTML
+// with an x/net/html tokenizer, buffering only the bytes of one matched
+// element at a time, and parses just that fragment to evaluate path and
+// decode it. The raw bytes of the whole document are already held in
+// memory by NewDecoder; beyond those, the memory Stream uses is bounded
+// by the size of one match rather than by a tree of the whole document.
+//
+// Each captured element yields at most one match, the first in document
+// order within it, so a match nested inside another match is not
+// returned on its own.
+//
+// Because of that, only a restricted shape of path is supported: it must
+// reduce to a single step naming a concrete tag, e.g. "//li" or
+// ".//div[@class='item']". A path with more than one step, or whose
+// final step doesn't name a concrete tag, returns an
+// UnsupportedStreamPathError, rather than evaluating each candidate
+// against its own isolated fragment and silently missing matches that
+// depend on ancestor context the fragment doesn't have.
+func (d *Decoder) Stream(path string) (*Iter, error) {
+	xpath, err := xmlpath.Compile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := streamTagName(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Iter{
+		xpath: xpath,
+		tok:   html.NewTokenizer(bytes.NewReader(d.raw)),
+		tag:   tag,
+	}, nil
+}
+
+// streamTagName extracts the concrete tag name Stream needs to recognize
+// match boundaries while scanning, or reports why path can't be
+// evaluated that way.
+func streamTagName(path string) (string, error) {
+	var steps []string
+	for _, part := range strings.Split(path, "/") {
+		if part == "" || part == "." {
+			continue
+		}
+		steps = append(steps, part)
+	}
+
+	if len(steps) != 1 {
+		return "", UnsupportedStreamPathError(path)
+	}
+
+	name, _ := leadingName(steps[0])
+	if name == "" || name == "*" || !isIdentifier(name) {
+		return "", UnsupportedStreamPathError(path)
+	}
+
+	return strings.ToLower(name), nil
+}
+
+// Iter yields the matches of a Stream xpath one at a time, scanning the
+// source incrementally rather than from an in-memory tree. A caller that
+// stops calling Next early never pays to buffer or decode the remaining
+// matches.
+type Iter struct {
+	xpath *xmlpath.Path
+	tok   *html.Tokenizer
+	tag   string
+
+	stack        []string
+	capturing    bool
+	captureDepth int
+	buf          bytes.Buffer
+
+	node *xmlpath.Node
+	err  error
+}
+
+// Next scans ahead to the next element matching the streamed path,
+// returning false once there are none left or the scan fails. Check Err
+// after a false return to tell the two apart.
+func (it *Iter) Next() bool {
+	for {
+		frag, ok := it.nextFragment()
+		if !ok {
+			return false
+		}
+
+		root, err := xmlpath.ParseHTML(bytes.NewReader(frag))
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		iter := it.xpath.Iter(root)
+		if !iter.Next() {
+			// The fragment's own markup didn't satisfy the rest of
+			// path, such as a predicate; keep scanning.
+			continue
+		}
+
+		it.node = iter.Node()
+		return true
+	}
+}
+
+// Err returns the first error encountered while scanning, if any.
+func (it *Iter) Err() error {
+	return it.err
+}
+
+// Decode unmarshals the current match into v, following the same rules
+// as Decoder.Unmarshal. Decode must only be called after a call to Next
+// that returned true.
+func (it *Iter) Decode(v interface{}) error {
+	st := &state{}
+
+	st.unmarshal(it.node, reflect.ValueOf(v))
+
+	return st.firstError
+}
+
+// nextFragment scans forward for the next complete occurrence of it.tag,
+// returning its raw bytes, including the opening and closing tag.
+//
+// It tracks nesting with a stack of open tag names built from the token
+// stream itself, rather than assuming it.tag's own content is well
+// formed: a closing tag found further up the stack than it.tag (an
+// ancestor closing early) or an implicit sibling close (see
+// selfClosingSiblings) both end the current match, synthesizing its
+// closing tag since the source never wrote one.
+func (it *Iter) nextFragment() ([]byte, bool) {
+	for {
+		tt := it.tok.Next()
+		if tt == html.ErrorToken {
+			if err := it.tok.Err(); err != io.EOF {
+				it.err = err
+			}
+			if it.capturing {
+				return it.finalizeFragment(false, nil), true
+			}
+			return nil, false
+		}
+
+		raw := it.tok.Raw()
+
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			nameBytes, _ := it.tok.TagName()
+			if frag, ok := it.handleStartTag(tt, string(nameBytes), raw); ok {
+				return frag, true
+			}
+		case html.EndTagToken:
+			nameBytes, _ := it.tok.TagName()
+			if frag, ok := it.handleEndTag(string(nameBytes), raw); ok {
+				return frag, true
+			}
+		default:
+			if it.capturing {
+				it.buf.Write(raw)
+			}
+		}
+	}
+}
+
+func (it *Iter) handleStartTag(tt html.TokenType, name string, raw []byte) ([]byte, bool) {
+	isVoid := tt == html.SelfClosingTagToken || voidElements[name]
+
+	var pending []byte
+	var havePending bool
+
+	if !isVoid && selfClosingSiblings[name] && len(it.stack) > 0 && it.stack[len(it.stack)-1] == name {
+		// name implicitly closes a still-open sibling of the same name,
+		// whether or not that sibling is the element being captured.
+		if it.capturing && name == it.tag && len(it.stack) == it.captureDepth {
+			pending = it.finalizeFragment(false, nil)
+			havePending = true
+		}
+		it.stack = it.stack[:len(it.stack)-1]
+	}
+
+	if !it.capturing && name == it.tag {
+		it.capturing = true
+		it.buf.Write(raw)
+		if isVoid {
+			return it.finalizeFragment(true, nil), true
+		}
+		it.stack = append(it.stack, name)
+		it.captureDepth = len(it.stack)
+		return pending, havePending
+	}
+
+	if it.capturing {
+		it.buf.Write(raw)
+	}
+	if !isVoid {
+		it.stack = append(it.stack, name)
+	}
+
+	return pending, havePending
+}
+
+func (it *Iter) handleEndTag(name string, raw []byte) ([]byte, bool) {
+	idx := -1
+	for i := len(it.stack) - 1; i >= 0; i-- {
+		if it.stack[i] == name {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		// Stray end tag with nothing open to match it.
+		if it.capturing {
+			it.buf.Write(raw)
+		}
+		return nil, false
+	}
+
+	if it.capturing && idx <= it.captureDepth-1 {
+		own := idx == it.captureDepth-1
+		frag := it.finalizeFragment(own, raw)
+		it.stack = it.stack[:idx]
+		return frag, true
+	}
+
+	if it.capturing {
+		it.buf.Write(raw)
+	}
+	it.stack = it.stack[:idx]
+	return nil, false
+}
+
+// finalizeFragment closes out the element currently being captured,
+// either with its own end tag (ownEndTag) or, if it was closed implicitly
+// or by an ancestor, a synthesized one, and returns its accumulated
+// bytes.
+func (it *Iter) finalizeFragment(ownEndTag bool, trigger []byte) []byte {
+	if ownEndTag {
+		it.buf.Write(trigger)
+	} else {
+		it.buf.WriteString("</" + it.tag + ">")
+	}
+
+	frag := append([]byte(nil), it.buf.Bytes()...)
+	it.buf.Reset()
+	it.capturing = false
+	return frag
+}
@@ -0,0 +1,123 @@
+package unhtml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	html := `<ul><li>0</li><li>1</li><li>2</li></ul>`
+
+	d, err := NewDecoder(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("Failed parsing html: %s", err)
+	}
+
+	it, err := d.Stream("//li")
+	if err != nil {
+		t.Fatalf("Stream returned error: %s", err)
+	}
+
+	var got []int
+	for it.Next() {
+		var n int
+		if err := it.Decode(&n); err != nil {
+			t.Fatalf("Decode returned error: %s", err)
+		}
+		got = append(got, n)
+	}
+
+	want := []int{0, 1, 2}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got[%d] = %d, want %d", i, got[i], want[i])
+		}
+	}
+}
+
+func TestStreamEarlyStop(t *testing.T) {
+	html := `<ul><li>0</li><li>1</li><li>2</li></ul>`
+
+	d, err := NewDecoder(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("Failed parsing html: %s", err)
+	}
+
+	it, err := d.Stream("//li")
+	if err != nil {
+		t.Fatalf("Stream returned error: %s", err)
+	}
+
+	if !it.Next() {
+		t.Fatal("expected a first match")
+	}
+
+	var n int
+	if err := it.Decode(&n); err != nil {
+		t.Fatalf("Decode returned error: %s", err)
+	}
+	if n != 0 {
+		t.Errorf("n = %d, want 0", n)
+	}
+	// Intentionally stop here without draining the remaining matches.
+}
+
+// TestStreamImplicitClose confirms Stream copes with the sibling-closes-
+// sibling pattern (<li> without a matching </li>) that real listing
+// pages commonly use, rather than merging every item into one match.
+func TestStreamImplicitClose(t *testing.T) {
+	html := `<ul><li>0<li>1<li>2</ul>`
+
+	d, err := NewDecoder(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("Failed parsing html: %s", err)
+	}
+
+	it, err := d.Stream("//li")
+	if err != nil {
+		t.Fatalf("Stream returned error: %s", err)
+	}
+
+	var got []int
+	for it.Next() {
+		var n int
+		if err := it.Decode(&n); err != nil {
+			t.Fatalf("Decode returned error: %s", err)
+		}
+		got = append(got, n)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Iter scan failed: %s", err)
+	}
+
+	want := []int{0, 1, 2}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got[%d] = %d, want %d", i, got[i], want[i])
+		}
+	}
+}
+
+// TestStreamUnsupportedPath confirms Stream rejects a path that depends
+// on ancestor context instead of silently evaluating each candidate in
+// isolation and missing matches.
+func TestStreamUnsupportedPath(t *testing.T) {
+	html := `<ul><li>0</li></ul>`
+
+	d, err := NewDecoder(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("Failed parsing html: %s", err)
+	}
+
+	if _, err := d.Stream("/ul/li"); err == nil {
+		t.Errorf("expected UnsupportedStreamPathError, got nil")
+	} else if _, ok := err.(UnsupportedStreamPathError); !ok {
+		t.Errorf("expected UnsupportedStreamPathError, got %T: %s", err, err)
+	}
+}
@@ -0,0 +1,97 @@
+package unhtml
+
+import "strings"
+
+// tagOptions holds the parsed pieces of an `unhtml` struct tag: the
+// xpath itself plus any comma-separated options, in the same spirit as
+// the options encoding/xml and encoding/json accept after the tag name.
+//
+// Recognised options:
+//
+//	,attr       the path must resolve to an attribute, not an element
+//	,innerhtml  fill a string or []byte field with the node's Bytes(),
+//	            the text of all its descendants; tags are not included
+//	,chardata   fill the field with only the node's direct child text nodes
+//	,omitempty  do not error when the path matches no nodes
+//	,key=EXPR   (maps only, see state.unmarshalMap) the per-entry key xpath
+type tagOptions struct {
+	path      string
+	attr      bool
+	innerhtml bool
+	chardata  bool
+	omitempty bool
+	key       string
+}
+
+// splitTagOptions splits a raw `unhtml` tag into its xpath and the
+// trailing comma-separated option string, without interpreting the
+// options. It is also used by the encoder, which only cares about path.
+func splitTagOptions(tag string) (path string, options string) {
+	if i := strings.IndexByte(tag, ','); i >= 0 {
+		return tag[:i], tag[i+1:]
+	}
+	return tag, ""
+}
+
+// parseFieldTag parses a full `unhtml` struct tag into its xpath and
+// options. An empty path is valid: it means "the current node", which is
+// mainly useful together with ,chardata or ,innerhtml.
+func parseFieldTag(tag string) tagOptions {
+	path, rest := splitTagOptions(tag)
+	opts := tagOptions{path: path}
+
+	if rest == "" {
+		return opts
+	}
+
+	for _, opt := range strings.Split(rest, ",") {
+		if k, v, ok := cutOption(opt); ok {
+			if k == "key" {
+				opts.key = v
+			}
+			continue
+		}
+
+		switch opt {
+		case "attr":
+			opts.attr = true
+		case "innerhtml":
+			opts.innerhtml = true
+		case "chardata":
+			opts.chardata = true
+		case "omitempty":
+			opts.omitempty = true
+		}
+	}
+
+	return opts
+}
+
+// cutOption splits a "key=value" option, reporting ok=false for bare
+// options such as "attr" that carry no value.
+func cutOption(opt string) (key, value string, ok bool) {
+	if i := strings.IndexByte(opt, '='); i >= 0 {
+		return opt[:i], opt[i+1:], true
+	}
+	return "", "", false
+}
+
+// isAttrPath reports whether the last step of an xpath expression
+// selects an attribute, i.e. it starts with "@". It is a heuristic, not
+// a full xpath parse, but matches every attribute selector this package
+// documents.
+func isAttrPath(path string) bool {
+	if i := strings.LastIndexByte(path, '/'); i >= 0 {
+		path = path[i+1:]
+	}
+	return strings.HasPrefix(path, "@")
+}
+
+// NotAttributeError is returned when a field tagged with the ,attr
+// option has a path whose last step does not select an attribute, as
+// judged by isAttrPath.
+type NotAttributeError string
+
+func (e NotAttributeError) Error() string {
+	return "unhtml: path does not select an attribute: " + string(e)
+}
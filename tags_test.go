@@ -0,0 +1,64 @@
+package unhtml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseFieldTag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want tagOptions
+	}{
+		{"div", tagOptions{path: "div"}},
+		{"div,innerhtml", tagOptions{path: "div", innerhtml: true}},
+		{",chardata", tagOptions{path: "", chardata: true}},
+		{"@href,attr", tagOptions{path: "@href", attr: true}},
+		{"div,omitempty", tagOptions{path: "div", omitempty: true}},
+		{"//user,key=@id", tagOptions{path: "//user", key: "@id"}},
+	}
+
+	for _, test := range tests {
+		got := parseFieldTag(test.tag)
+		if got != test.want {
+			t.Errorf("parseFieldTag(%q) = %+v, want %+v", test.tag, got, test.want)
+		}
+	}
+}
+
+func TestIsAttrPath(t *testing.T) {
+	if !isAttrPath("@href") {
+		t.Error("expected @href to be an attribute path")
+	}
+	if !isAttrPath("div/@href") {
+		t.Error("expected div/@href to be an attribute path")
+	}
+	if isAttrPath("div") {
+		t.Error("expected div to not be an attribute path")
+	}
+}
+
+func TestUnmarshalChardata(t *testing.T) {
+	type Test struct {
+		A string `unhtml:",chardata"`
+	}
+
+	html := `<div><p>Hello</p> World <b>!</b></div>`
+
+	d, err := NewDecoder(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("Failed parsing html: %s", err)
+	}
+
+	var result Test
+	if err := d.UnmarshalRelative("/div", &result); err != nil {
+		t.Fatalf("Failed unmarshalling: %s", err)
+	}
+
+	// Unlike the default string extraction, which concatenates text from
+	// every descendant ("Hello World !"), ,chardata only collects text
+	// nodes that are direct children of the matched element.
+	if want := " World "; result.A != want {
+		t.Errorf("A = %q, want %q", result.A, want)
+	}
+}
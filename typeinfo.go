@@ -0,0 +1,153 @@
+package unhtml
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"gopkg.in/xmlpath.v1"
+)
+
+// typeInfo is the cached, precomputed result of inspecting a struct
+// type's `unhtml` tags: one fieldInfo per tagged, exported field, in
+// declaration order. Building it involves reflection and xpath
+// compilation, both of which are too expensive to repeat on every
+// Unmarshal call, so it is cached in typeInfoCache keyed by type.
+type typeInfo struct {
+	fields []fieldInfo
+}
+
+// fieldInfo is everything unmarshalStruct needs to fill one field,
+// precomputed once per struct type.
+type fieldInfo struct {
+	index   int // index into reflect.Value.Field
+	opts    tagOptions
+	path    *xmlpath.Path // nil if opts.path == ""
+	keyPath *xmlpath.Path // compiled opts.key, set only for map fields
+}
+
+// MissingMapKeyError is returned when a map field has no ,key= tag
+// option to compute an entry's key from its matched node.
+type MissingMapKeyError string
+
+func (e MissingMapKeyError) Error() string {
+	return "unhtml: map field requires a ,key= tag option: " + string(e)
+}
+
+// typeInfoCache caches typeInfo by reflect.Type, analogous to the
+// typeinfo cache encoding/xml keeps for marshalling and unmarshalling.
+var typeInfoCache sync.Map // map[reflect.Type]*typeInfo
+
+// getTypeInfo returns the cached typeInfo for t, building and storing it
+// on first use. t must be a struct type.
+func getTypeInfo(t reflect.Type) (*typeInfo, error) {
+	if cached, ok := typeInfoCache.Load(t); ok {
+		return cached.(*typeInfo), nil
+	}
+
+	info, err := buildTypeInfo(t)
+	if err != nil {
+		return nil, err
+	}
+
+	actual, _ := typeInfoCache.LoadOrStore(t, info)
+	return actual.(*typeInfo), nil
+}
+
+// buildTypeInfo walks t's exported fields, parses their `unhtml` tags
+// and compiles the resulting xpaths.
+func buildTypeInfo(t reflect.Type) (*typeInfo, error) {
+	info := &typeInfo{}
+
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+
+		if structField.PkgPath != "" {
+			// Unexported: unmarshalStruct could never set it anyway.
+			continue
+		}
+
+		rawTag := structField.Tag.Get("unhtml")
+		rawCSS := structField.Tag.Get("uncss")
+
+		if rawTag == "" && rawCSS == "" {
+			continue
+		}
+
+		var opts tagOptions
+		switch {
+		case rawCSS != "":
+			opts = parseFieldTag(rawCSS)
+			xpath, err := cssToXPath(opts.path)
+			if err != nil {
+				return nil, err
+			}
+			opts.path = xpath
+		case strings.HasPrefix(rawTag, cssPathPrefix):
+			opts = parseFieldTag(strings.TrimPrefix(rawTag, cssPathPrefix))
+			xpath, err := cssToXPath(opts.path)
+			if err != nil {
+				return nil, err
+			}
+			opts.path = xpath
+		default:
+			opts = parseFieldTag(rawTag)
+		}
+
+		fi := fieldInfo{index: i, opts: opts}
+
+		if opts.path != "" {
+			path, err := xmlpath.Compile(opts.path)
+			if err != nil {
+				return nil, err
+			}
+			fi.path = path
+		}
+
+		if structField.Type.Kind() == reflect.Map {
+			if opts.key == "" {
+				return nil, MissingMapKeyError(structField.Name)
+			}
+
+			keyPath, err := xmlpath.Compile(opts.key)
+			if err != nil {
+				return nil, err
+			}
+			fi.keyPath = keyPath
+		}
+
+		info.fields = append(info.fields, fi)
+	}
+
+	return info, nil
+}
+
+// Precompile compiles the `unhtml` and `uncss` paths of the fields of
+// v's struct type and caches the result the same way Unmarshal would.
+// Calling it up front reports a malformed path at startup, rather than
+// as the error of the first Unmarshal call that reaches the type.
+//
+// v may be a struct, a pointer to one, or anything containing one (a
+// slice, map or pointer thereof); Precompile looks through those to find
+// the struct type to inspect. Only that one struct type is compiled:
+// struct types of its fields are compiled when Unmarshal first reaches
+// them.
+func Precompile(v interface{}) error {
+	t := reflect.TypeOf(v)
+
+	for t != nil {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+			continue
+		}
+		break
+	}
+
+	if t == nil || t.Kind() != reflect.Struct {
+		return &InvalidUnmarshalError{Type: reflect.TypeOf(v)}
+	}
+
+	_, err := getTypeInfo(t)
+	return err
+}
@@ -0,0 +1,42 @@
+package unhtml
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetTypeInfoCaches(t *testing.T) {
+	type Commit struct {
+		Title string `unhtml:"p/a/@title"`
+	}
+
+	typ := reflect.TypeOf(Commit{})
+
+	first, err := getTypeInfo(typ)
+	if err != nil {
+		t.Fatalf("getTypeInfo returned error: %s", err)
+	}
+
+	second, err := getTypeInfo(typ)
+	if err != nil {
+		t.Fatalf("getTypeInfo returned error: %s", err)
+	}
+
+	if first != second {
+		t.Error("expected getTypeInfo to return the cached *typeInfo on the second call")
+	}
+
+	if len(first.fields) != 1 || first.fields[0].path == nil {
+		t.Errorf("unexpected typeInfo: %+v", first)
+	}
+}
+
+func TestPrecompileInvalidPath(t *testing.T) {
+	type Bad struct {
+		Title string `unhtml:"p[invalid("`
+	}
+
+	if err := Precompile(Bad{}); err == nil {
+		t.Error("expected Precompile to return an error for an invalid xpath")
+	}
+}